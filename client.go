@@ -0,0 +1,345 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// Client abstracts how this package talks to a LINSTOR controller, whether
+// that's its REST API or the linstor CLI. Implementations must be safe for
+// concurrent use so that a single Client can be shared across Resources.
+type Client interface {
+	// CreateResourceDefinition reserves a resource name on the controller.
+	CreateResourceDefinition(ctx context.Context, name string) error
+	// CreateVolumeDefinition reserves a volume of sizeKiB on an existing
+	// resource definition.
+	CreateVolumeDefinition(ctx context.Context, name string, sizeKiB uint64) error
+	// CreateResource deploys name on node, disklessly if diskless is true.
+	CreateResource(ctx context.Context, name, node, storagePool string, diskless bool) error
+	// DeleteResource removes name from a single node.
+	DeleteResource(ctx context.Context, name, node string) error
+	// DeleteResourceDefinition removes name from every node.
+	DeleteResourceDefinition(ctx context.Context, name string) error
+	// ListResources returns the controller's view of every deployed resource.
+	ListResources(ctx context.Context) (resList, error)
+	// SetVolumeDefinitionSize grows (or shrinks) volume 0 of name to sizeKiB.
+	SetVolumeDefinitionSize(ctx context.Context, name string, sizeKiB uint64) error
+	// CreateSnapshot snapshots resourceName as snapName.
+	CreateSnapshot(ctx context.Context, resourceName, snapName string) error
+	// DeleteSnapshot removes snapName from resourceName.
+	DeleteSnapshot(ctx context.Context, resourceName, snapName string) error
+	// ListSnapshots returns every snapshot of resourceName.
+	ListSnapshots(ctx context.Context, resourceName string) (snapshotList, error)
+	// RestoreSnapshot creates a new resource definition named targetName
+	// from resourceName's snapName.
+	RestoreSnapshot(ctx context.Context, resourceName, snapName, targetName string) error
+	// RollbackSnapshot rolls resourceName back to snapName.
+	RollbackSnapshot(ctx context.Context, resourceName, snapName string) error
+}
+
+// DefaultClient is the Client used by Resource methods that don't set
+// Resource.Client. It shells out to the linstor CLI, preserving the
+// package's historical behavior.
+var DefaultClient Client = NewCLIClient()
+
+// CLIClient implements Client by shelling out to the linstor CLI, the way
+// this package has always worked. It's useful as a fallback when a
+// controller's REST API isn't reachable, and for environments that already
+// have linstor-client installed and configured.
+type CLIClient struct{}
+
+// NewCLIClient returns a Client that shells out to the linstor CLI.
+func NewCLIClient() *CLIClient {
+	return &CLIClient{}
+}
+
+func (c *CLIClient) run(ctx context.Context, args ...string) error {
+	args = append([]string{"-m"}, args...)
+	out, err := exec.CommandContext(ctx, "linstor", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v : %s", err, out)
+	}
+
+	s := returnStatuses{}
+	if err := json.Unmarshal(out, &s); err != nil {
+		return fmt.Errorf("couldn't Unmarshal %s :%v", out, err)
+	}
+
+	return s.validate()
+}
+
+func (c *CLIClient) CreateResourceDefinition(ctx context.Context, name string) error {
+	return c.run(ctx, "create-resource-definition", name)
+}
+
+func (c *CLIClient) CreateVolumeDefinition(ctx context.Context, name string, sizeKiB uint64) error {
+	return c.run(ctx, "create-volume-definition", name, fmt.Sprintf("%dkib", sizeKiB))
+}
+
+func (c *CLIClient) CreateResource(ctx context.Context, name, node, storagePool string, diskless bool) error {
+	if diskless {
+		return c.run(ctx, "create-resource", name, node, "--diskless")
+	}
+	return c.run(ctx, "create-resource", name, node, "-s", storagePool)
+}
+
+func (c *CLIClient) DeleteResource(ctx context.Context, name, node string) error {
+	return c.run(ctx, "delete-resource", name, node)
+}
+
+func (c *CLIClient) DeleteResourceDefinition(ctx context.Context, name string) error {
+	return c.run(ctx, "delete-resource-definition", name)
+}
+
+func (c *CLIClient) SetVolumeDefinitionSize(ctx context.Context, name string, sizeKiB uint64) error {
+	return c.run(ctx, "set-volume-definition-size", name, fmt.Sprintf("%dkib", sizeKiB))
+}
+
+func (c *CLIClient) CreateSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return c.run(ctx, "create-snapshot", resourceName, snapName)
+}
+
+func (c *CLIClient) DeleteSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return c.run(ctx, "delete-snapshot", resourceName, snapName)
+}
+
+func (c *CLIClient) RestoreSnapshot(ctx context.Context, resourceName, snapName, targetName string) error {
+	return c.run(ctx, "restore-snapshot", resourceName, snapName, targetName)
+}
+
+func (c *CLIClient) RollbackSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return c.run(ctx, "rollback-snapshot", resourceName, snapName)
+}
+
+func (c *CLIClient) ListSnapshots(ctx context.Context, resourceName string) (snapshotList, error) {
+	out, err := exec.CommandContext(ctx, "linstor", "-m", "list-snapshots", resourceName).CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	l := snapshotList{}
+	if err := json.Unmarshal(out, &l); err != nil {
+		return nil, fmt.Errorf("couldn't Unmarshal %s :%v", out, err)
+	}
+	return l, nil
+}
+
+func (c *CLIClient) ListResources(ctx context.Context) (resList, error) {
+	out, err := exec.CommandContext(ctx, "linstor", "-m", "ls-rsc").CombinedOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	l := resList{}
+	if err := json.Unmarshal(out, &l); err != nil {
+		return nil, fmt.Errorf("couldn't Unmarshal %s :%v", out, err)
+	}
+	return l, nil
+}
+
+// Option configures a ControllerClient constructed by NewControllerClient.
+type Option func(*ControllerClient)
+
+// WithHTTPClient overrides the *http.Client used to talk to the controller.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *ControllerClient) { c.httpClient = h }
+}
+
+// WithTimeout sets the per-request timeout applied to controller calls.
+// The default is 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(c *ControllerClient) { c.timeout = d }
+}
+
+// WithRetries sets how many additional times a failed controller call is
+// retried before giving up. The default is 2 retries (3 attempts total).
+func WithRetries(n int) Option {
+	return func(c *ControllerClient) { c.retries = n }
+}
+
+// ControllerClient implements Client by talking directly to a LINSTOR
+// controller's REST API, rather than shelling out to the linstor CLI.
+type ControllerClient struct {
+	endpoint   string
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+}
+
+// NewControllerClient returns a Client backed by the REST API exposed by the
+// controller at endpoint, e.g. "http://localhost:3370".
+func NewControllerClient(endpoint string, opts ...Option) *ControllerClient {
+	c := &ControllerClient{
+		endpoint:   endpoint,
+		httpClient: http.DefaultClient,
+		timeout:    30 * time.Second,
+		retries:    2,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do issues method/path against the controller, retrying any failed attempt
+// (including errors reported by LINSTOR itself) up to c.retries more times,
+// and decodes the response body into out if it's non-nil. On a non-2xx
+// response, the body is decoded as returnStatuses and validated the same way
+// CLIClient.run does, so callers get a LinstorError/MultiError they can
+// inspect with IsAlreadyExists, IsNotFound, etc. instead of an opaque string.
+func (c *ControllerClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("couldn't marshal request body: %v", err)
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		req, err := http.NewRequestWithContext(reqCtx, method, c.endpoint+path, bytes.NewReader(payload))
+		if err != nil {
+			cancel()
+			return fmt.Errorf("couldn't build request for %s %s: %v", method, path, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		cancel()
+		if err != nil {
+			lastErr = fmt.Errorf("%s %s: %v", method, path, err)
+			continue
+		}
+
+		func() {
+			defer resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				s := returnStatuses{}
+				if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+					lastErr = fmt.Errorf("%s %s: unexpected status %s", method, path, resp.Status)
+					return
+				}
+				if verr := s.validate(); verr != nil {
+					lastErr = verr
+					return
+				}
+				lastErr = fmt.Errorf("%s %s: unexpected status %s", method, path, resp.Status)
+				return
+			}
+			lastErr = nil
+			if out != nil {
+				lastErr = json.NewDecoder(resp.Body).Decode(out)
+			}
+		}()
+
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	return lastErr
+}
+
+func (c *ControllerClient) CreateResourceDefinition(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/v1/resource-definitions", map[string]interface{}{
+		"resource_definition": map[string]string{"name": name},
+	}, nil)
+}
+
+func (c *ControllerClient) CreateVolumeDefinition(ctx context.Context, name string, sizeKiB uint64) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/resource-definitions/%s/volume-definitions", name), map[string]interface{}{
+		"volume_definition": map[string]uint64{"size_kib": sizeKiB},
+	}, nil)
+}
+
+func (c *ControllerClient) CreateResource(ctx context.Context, name, node, storagePool string, diskless bool) error {
+	props := map[string]interface{}{
+		"resource": map[string]interface{}{
+			"node_name": node,
+			"name":      name,
+		},
+	}
+	if diskless {
+		props["resource"].(map[string]interface{})["flags"] = []string{"DISKLESS"}
+	} else {
+		props["resource"].(map[string]interface{})["props"] = map[string]string{"StorPoolName": storagePool}
+	}
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/resource-definitions/%s/resources", name), props, nil)
+}
+
+func (c *ControllerClient) DeleteResource(ctx context.Context, name, node string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/resource-definitions/%s/resources/%s", name, node), nil, nil)
+}
+
+func (c *ControllerClient) DeleteResourceDefinition(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/resource-definitions/%s", name), nil, nil)
+}
+
+func (c *ControllerClient) SetVolumeDefinitionSize(ctx context.Context, name string, sizeKiB uint64) error {
+	return c.do(ctx, http.MethodPut, fmt.Sprintf("/v1/resource-definitions/%s/volume-definitions/0", name), map[string]interface{}{
+		"size_kib": sizeKiB,
+	}, nil)
+}
+
+func (c *ControllerClient) ListResources(ctx context.Context) (resList, error) {
+	l := resList{}
+	if err := c.do(ctx, http.MethodGet, "/v1/view/resources", nil, &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (c *ControllerClient) CreateSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/resource-definitions/%s/snapshots", resourceName), map[string]interface{}{
+		"name": snapName,
+	}, nil)
+}
+
+func (c *ControllerClient) DeleteSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/v1/resource-definitions/%s/snapshots/%s", resourceName, snapName), nil, nil)
+}
+
+func (c *ControllerClient) RestoreSnapshot(ctx context.Context, resourceName, snapName, targetName string) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/resource-definitions/%s/snapshot-restore-resource", resourceName), map[string]interface{}{
+		"name":        snapName,
+		"to_resource": targetName,
+	}, nil)
+}
+
+func (c *ControllerClient) RollbackSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/resource-definitions/%s/snapshot-rollback/%s", resourceName, snapName), nil, nil)
+}
+
+func (c *ControllerClient) ListSnapshots(ctx context.Context, resourceName string) (snapshotList, error) {
+	l := snapshotList{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v1/resource-definitions/%s/snapshots", resourceName), nil, &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
@@ -0,0 +1,126 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import "strings"
+
+// LinstorError is a single failed operation reported back by the
+// controller, decoded from its ret_code and message instead of surfaced as
+// an opaque blob of JSON.
+type LinstorError struct {
+	RetCode   uint64
+	Message   string
+	Cause     string
+	Details   string
+	ObjRefs   map[string]string
+	Variables map[string]string
+}
+
+func newLinstorError(s returnStatus) *LinstorError {
+	objRefs := make(map[string]string, len(s.ObjRefs))
+	for _, ref := range s.ObjRefs {
+		objRefs[ref.Key] = ref.Value
+	}
+
+	vars := make(map[string]string, len(s.Variables))
+	for _, v := range s.Variables {
+		vars[v.Key] = v.Value
+	}
+
+	return &LinstorError{
+		RetCode:   s.RetCode,
+		Message:   s.MessageFormat,
+		Cause:     s.CauseFormat,
+		Details:   s.DetailsFormat,
+		ObjRefs:   objRefs,
+		Variables: vars,
+	}
+}
+
+func (e *LinstorError) Error() string {
+	if e.Cause != "" {
+		return e.Message + ": " + e.Cause
+	}
+	return e.Message
+}
+
+// text reports whether any of e's human-readable fields contain s,
+// case-insensitively.
+//
+// LINSTOR's REST API doesn't document a stable, object-independent numeric
+// sub-code within ret_code: the same failure (e.g. "already exists") is
+// raised with a different ret_code per object type (resource, volume,
+// node, snapshot, ...), and neither linstor-server nor the REST API spec
+// publishes the full cross product. What LINSTOR does keep stable across
+// versions is the wording of message_format/details_format/cause_format,
+// since its own CLI and GUI print those strings directly to the user. The
+// Is* predicates below classify on that wording instead of guessing at
+// ret_code bits we have no way to verify.
+func (e *LinstorError) text(s string) bool {
+	haystack := strings.ToLower(e.Message + " " + e.Details + " " + e.Cause)
+	return strings.Contains(haystack, s)
+}
+
+// IsAlreadyExists reports whether the operation failed because the object
+// it was trying to create already exists.
+func (e *LinstorError) IsAlreadyExists() bool {
+	return e.text("already exists")
+}
+
+// IsNotFound reports whether the operation failed because the object it
+// referred to doesn't exist.
+func (e *LinstorError) IsNotFound() bool {
+	return e.text("not found") || e.text("unknown resource") || e.text("unknown node")
+}
+
+// IsInUse reports whether the operation failed because the object it
+// referred to is still in use.
+func (e *LinstorError) IsInUse() bool {
+	return e.text("in use") || e.text("still has")
+}
+
+// IsInsufficientSpace reports whether the operation failed because no
+// storage pool had enough free space to satisfy it.
+func (e *LinstorError) IsInsufficientSpace() bool {
+	return e.text("not enough") && (e.text("space") || e.text("storage"))
+}
+
+// MultiError aggregates one LinstorError per failed operation in a single
+// linstor/controller call.
+type MultiError []*LinstorError
+
+func (m MultiError) Error() string {
+	msgs := make([]string, 0, len(m))
+	for _, e := range m {
+		msgs = append(msgs, e.Error())
+	}
+	return "error status from one or more linstor operations: " + strings.Join(msgs, "; ")
+}
+
+// Any reports whether any error in m satisfies pred, so callers can ask
+// "did any of these operations fail because the object already existed"
+// without caring which one.
+func (m MultiError) Any(pred func(*LinstorError) bool) bool {
+	for _, e := range m {
+		if pred(e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import "testing"
+
+func TestLinstorErrorPredicates(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      *LinstorError
+		exists   bool
+		notFound bool
+		inUse    bool
+		noSpace  bool
+	}{
+		{
+			name:   "resource already exists",
+			err:    &LinstorError{Message: "Resource 'foo' already exists."},
+			exists: true,
+		},
+		{
+			name:     "node not found",
+			err:      &LinstorError{Message: "Node 'bar' not found."},
+			notFound: true,
+		},
+		{
+			name:     "unknown resource",
+			err:      &LinstorError{Message: "Unknown resource 'baz'."},
+			notFound: true,
+		},
+		{
+			name:  "resource still in use",
+			err:   &LinstorError{Message: "Resource 'foo' is still in use."},
+			inUse: true,
+		},
+		{
+			name:  "volume definition still has snapshots",
+			err:   &LinstorError{Message: "Volume definition 'foo' still has snapshots."},
+			inUse: true,
+		},
+		{
+			name:    "not enough free space",
+			err:     &LinstorError{Message: "Not enough free space available on any storage pool."},
+			noSpace: true,
+		},
+		{
+			name: "unrelated failure matches nothing",
+			err:  &LinstorError{Message: "Unable to connect to satellite."},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.err.IsAlreadyExists(); got != c.exists {
+				t.Errorf("IsAlreadyExists() = %v, want %v", got, c.exists)
+			}
+			if got := c.err.IsNotFound(); got != c.notFound {
+				t.Errorf("IsNotFound() = %v, want %v", got, c.notFound)
+			}
+			if got := c.err.IsInUse(); got != c.inUse {
+				t.Errorf("IsInUse() = %v, want %v", got, c.inUse)
+			}
+			if got := c.err.IsInsufficientSpace(); got != c.noSpace {
+				t.Errorf("IsInsufficientSpace() = %v, want %v", got, c.noSpace)
+			}
+		})
+	}
+}
+
+func TestMultiErrorAny(t *testing.T) {
+	m := MultiError{
+		&LinstorError{Message: "Unable to connect to satellite."},
+		&LinstorError{Message: "Resource 'foo' already exists."},
+	}
+
+	if !m.Any((*LinstorError).IsAlreadyExists) {
+		t.Error("expected Any(IsAlreadyExists) to find the second error")
+	}
+	if m.Any((*LinstorError).IsInUse) {
+		t.Error("expected Any(IsInUse) to find nothing")
+	}
+}
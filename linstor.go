@@ -19,7 +19,7 @@
 package linstor
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -30,6 +30,12 @@ import (
 // Resource contains all the information needed to query and assign/deploy
 // a resource. If you're deploying a resource, Redundancy is required. If you're
 // assigning a resource to a particular node, NodeName is required.
+//
+// Client is the Client used to reach the LINSTOR controller. If nil,
+// DefaultClient is used.
+//
+// Mounter is the Mounter used by Publish, Unpublish, and FSUtils built from
+// this Resource. If nil, an OSMounter is used.
 type Resource struct {
 	Name        string
 	NodeName    string
@@ -38,6 +44,26 @@ type Resource struct {
 	ClientList  []string
 	StoragePool string
 	SizeKiB     uint64
+	Client      Client
+	Mounter     Mounter
+}
+
+// client returns the Client this Resource should use, falling back to
+// DefaultClient if none was set.
+func (r Resource) client() Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return DefaultClient
+}
+
+// mounter returns the Mounter this Resource should use, falling back to an
+// OSMounter if none was set.
+func (r Resource) mounter() Mounter {
+	if r.Mounter != nil {
+		return r.Mounter
+	}
+	return NewOSMounter()
 }
 
 type resList []struct {
@@ -84,7 +110,9 @@ type volInfo struct {
 	VlmNr         int  `json:"vlm_nr"`
 }
 
-type returnStatuses []struct {
+type returnStatuses []returnStatus
+
+type returnStatus struct {
 	DetailsFormat string `json:"details_format"`
 	MessageFormat string `json:"message_format"`
 	CauseFormat   string `json:"cause_format,omitempty"`
@@ -100,16 +128,18 @@ type returnStatuses []struct {
 }
 
 func (s returnStatuses) validate() error {
+	var errs MultiError
+
 	for _, message := range s {
 		if !linstorSuccess(message.RetCode) {
-			msg, err := json.Marshal(s)
-			if err != nil {
-				return err
-			}
-			return fmt.Errorf("error status from one or more linstor operations: %s", msg)
+			errs = append(errs, newLinstorError(message))
 		}
 	}
-	return nil
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
 
 func linstorSuccess(retcode uint64) bool {
@@ -133,36 +163,18 @@ func (r Resource) CreateAndAssign() error {
 	return nil
 }
 
-// Only use this for things that return the normal returnStatuses json.
-func linstor(args ...string) error {
-	args = append([]string{"-m"}, args...)
-	out, err := exec.Command("linstor", args...).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%v : %s", err, out)
-	}
-
-	s := returnStatuses{}
-	if err := json.Unmarshal(out, &s); err != nil {
-		return fmt.Errorf("couldn't Unmarshal %s :%v", out, err)
-	}
-
-	if err := s.validate(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // Create reserves the resource name in Linstor.
 func (r Resource) Create() error {
-	if err := linstor("create-resource-definition", r.Name); err != nil {
-		return fmt.Errorf("unable to reserve resource name %s :%v", r.Name, err)
+	ctx := context.Background()
+
+	if err := r.client().CreateResourceDefinition(ctx, r.Name); err != nil {
+		return fmt.Errorf("unable to reserve resource name %s :%w", r.Name, err)
 	}
 
 	time.Sleep(time.Second * 2)
 
-	if err := linstor("create-volume-definition", r.Name, fmt.Sprintf("%dkib", r.SizeKiB)); err != nil {
-		return fmt.Errorf("unable to reserve resource name %s :%v", r.Name, err)
+	if err := r.client().CreateVolumeDefinition(ctx, r.Name, r.SizeKiB); err != nil {
+		return fmt.Errorf("unable to reserve resource name %s :%w", r.Name, err)
 	}
 
 	return nil
@@ -180,13 +192,15 @@ func (r Resource) Assign() error {
 		return fmt.Errorf("No resource definition for resource %s", r.Name)
 	}
 
+	ctx := context.Background()
+
 	for _, node := range r.NodeList {
 		present, err := r.OnNode(node)
 		if err != nil {
 			return fmt.Errorf("unable to assign resource %s failed to check if it was already present on node %s: %v", r.Name, node, err)
 		}
 		if !present {
-			if err = linstor("create-resource", r.Name, node, "-s", r.StoragePool); err != nil {
+			if err = r.client().CreateResource(ctx, r.Name, node, r.StoragePool, false); err != nil {
 				return err
 			}
 		}
@@ -198,7 +212,7 @@ func (r Resource) Assign() error {
 			return fmt.Errorf("unable to assign resource %s failed to check if it was already present on node %s: %v", r.Name, node, err)
 		}
 		if !present {
-			if err = linstor("create-resource", r.Name, node, "--diskless"); err != nil {
+			if err = r.client().CreateResource(ctx, r.Name, node, "", true); err != nil {
 				return err
 			}
 		}
@@ -209,40 +223,36 @@ func (r Resource) Assign() error {
 
 // Unassign unassigns a resource from a particular node.
 func (r Resource) Unassign(nodeName string) error {
-	if err := linstor("delete-resource", r.Name, nodeName); err != nil {
-		return fmt.Errorf("failed to unassign resource %s from node %s: %v", r.Name, nodeName, err)
+	if err := r.client().DeleteResource(context.Background(), r.Name, nodeName); err != nil {
+		return fmt.Errorf("failed to unassign resource %s from node %s: %w", r.Name, nodeName, err)
 	}
 	return nil
 }
 
 // Delete removes a resource entirely from all nodes.
 func (r Resource) Delete() error {
-	if err := linstor("delete-resource-definition", r.Name); err != nil {
-		return fmt.Errorf("failed to delete resource %s: %v", r.Name, err)
+	if err := r.client().DeleteResourceDefinition(context.Background(), r.Name); err != nil {
+		return fmt.Errorf("failed to delete resource %s: %w", r.Name, err)
 	}
 	return nil
 }
 
 // Exists checks to see if a resource is defined in DRBD Manage.
 func (r Resource) Exists() (bool, error) {
-	out, err := exec.Command("linstor", "-m", "ls-rsc").CombinedOutput()
+	list, err := r.client().ListResources(context.Background())
 	if err != nil {
 		return false, err
 	}
 
-	// Inject real implementations here, test through the internal function.
-	return doResExists(r.Name, out)
+	return doResExists(r.Name, list)
 }
 
-func doResExists(resourceName string, resInfo []byte) (bool, error) {
-	resources := resList{}
-
-	err := json.Unmarshal(resInfo, &resources)
-	if err != nil {
-		return false, fmt.Errorf("couldn't Unmarshal %s :%v", resInfo, err)
+func doResExists(resourceName string, list resList) (bool, error) {
+	if len(list) == 0 {
+		return false, nil
 	}
 
-	for _, r := range resources[0].Resources {
+	for _, r := range list[0].Resources {
 		if r.Name == resourceName {
 			return true, nil
 		}
@@ -253,20 +263,19 @@ func doResExists(resourceName string, resInfo []byte) (bool, error) {
 
 //OnNode determines if a resource is present on a particular node.
 func (r Resource) OnNode(nodeName string) (bool, error) {
-	out, err := exec.Command("linstor", "-m", "ls-rsc").CombinedOutput()
+	list, err := r.client().ListResources(context.Background())
 	if err != nil {
 		return false, err
 	}
 
-	l := resList{}
-	if err := json.Unmarshal(out, &l); err != nil {
-		return false, fmt.Errorf("couldn't Unmarshal %s :%v", out, err)
-	}
-
-	return doResOnNode(l, r.Name, nodeName), nil
+	return doResOnNode(list, r.Name, nodeName), nil
 }
 
 func doResOnNode(list resList, resName, nodeName string) bool {
+	if len(list) == 0 {
+		return false
+	}
+
 	for _, res := range list[0].Resources {
 		if res.Name == resName && res.NodeName == nodeName {
 			return true
@@ -277,10 +286,8 @@ func doResOnNode(list resList, resName, nodeName string) bool {
 
 // IsClient determines if resource is running as a client on nodeName.
 func (r Resource) IsClient(nodeName string) bool {
-	out, _ := exec.Command("linstor", "-m", "ls-rsc").CombinedOutput()
-
-	list := resList{}
-	if err := json.Unmarshal(out, &list); err != nil {
+	list, err := r.client().ListResources(context.Background())
+	if err != nil {
 		return false
 	}
 
@@ -288,6 +295,10 @@ func (r Resource) IsClient(nodeName string) bool {
 }
 
 func (r Resource) doIsClient(list resList, nodeName string) bool {
+	if len(list) == 0 {
+		return false
+	}
+
 	// Traverse all the volume states to find volume 0 of our resource on nodeName.
 	// Assume volume 0 is the one we want.
 	for _, res := range list[0].ResourceStates {
@@ -309,21 +320,36 @@ func EnoughFreeSpace(requestedKiB, replicas string) error {
 }
 
 // FSUtil handles creating a filesystem and mounting resources.
+//
+// Mounter is the Mounter used to format and mount the resource's device. If
+// nil, the Resource's Mounter is used (which is an OSMounter if that is nil
+// too).
 type FSUtil struct {
 	*Resource
-	FSType string
+	FSType  string
+	Mounter Mounter
 }
 
-// Mount the FSUtil's resource on the path.
-func (f FSUtil) Mount(path string) error {
-	device, err := WaitForDevPath(*f.Resource, 3)
-	if err != nil {
-		return fmt.Errorf("unable to mount device, couldn't find Resource device path: %v", err)
+// mounter returns the Mounter this FSUtil should use: its own Mounter if
+// set, otherwise its Resource's (which falls back to an OSMounter in turn).
+func (f FSUtil) mounter() Mounter {
+	if f.Mounter != nil {
+		return f.Mounter
 	}
+	if f.Resource != nil {
+		return f.Resource.mounter()
+	}
+	return NewOSMounter()
+}
 
-	err = f.safeFormat(device)
+// Mount the FSUtil's resource on the path. options are passed straight
+// through to the mount command, e.g. "ro", "noatime", "discard". If bind is
+// true, the device is bind-mounted onto path as-is instead of being
+// formatted with FSType.
+func (f FSUtil) Mount(path string, bind bool, options []string) error {
+	device, err := WaitForDevPath(*f.Resource, 3)
 	if err != nil {
-		return fmt.Errorf("unable to mount device: %v", err)
+		return fmt.Errorf("unable to mount device, couldn't find Resource device path: %v", err)
 	}
 
 	out, err := exec.Command("mkdir", "-p", path).CombinedOutput()
@@ -331,57 +357,23 @@ func (f FSUtil) Mount(path string) error {
 		return fmt.Errorf("unable to mount device, failed to make mount directory: %v: %s", err, out)
 	}
 
-	out, err = exec.Command("mount", device, path).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("unable to mount device: %v: %s", err, out)
-	}
-
-	return nil
-}
-
-// UnMount the FSUtil's resource from the path.
-func (f FSUtil) UnMount(path string) error {
-	// If the path isn't a directory, we're not mounted there.
-	_, err := exec.Command("test", "-d", path).CombinedOutput()
-	if err != nil {
-		return nil
-	}
-
-	// If the path isn't mounted, then we're not mounted.
-	_, err = exec.Command("findmnt", "-f", path).CombinedOutput()
-	if err != nil {
+	if bind {
+		if err := f.mounter().Mount(device, path, "", append([]string{"bind"}, options...)); err != nil {
+			return fmt.Errorf("unable to bind mount device: %v", err)
+		}
 		return nil
 	}
 
-	out, err := exec.Command("umount", path).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("unable to unmount device: %q: %s", err, out)
+	if err := f.mounter().SafeFormatAndMount(device, path, f.FSType, options); err != nil {
+		return fmt.Errorf("unable to mount device: %v", err)
 	}
 
 	return nil
 }
 
-func (f FSUtil) safeFormat(path string) error {
-	deviceFS, err := checkFSType(path)
-	if err != nil {
-		return fmt.Errorf("unable to format filesystem for %q: %v", path, err)
-	}
-
-	// Device is formatted correctly already.
-	if deviceFS == f.FSType {
-		return nil
-	}
-
-	if deviceFS != "" && deviceFS != f.FSType {
-		return fmt.Errorf("device %q already formatted with %q filesystem, refusing to overwrite with %q filesystem", path, deviceFS, f.FSType)
-	}
-
-	out, err := exec.Command("mkfs", "-t", f.FSType, path).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("couldn't create %s filesystem %v: %q", f.FSType, err, out)
-	}
-
-	return nil
+// UnMount the FSUtil's resource from the path.
+func (f FSUtil) UnMount(path string) error {
+	return f.mounter().Unmount(path)
 }
 
 func checkFSType(dev string) (string, error) {
@@ -438,24 +430,21 @@ func WaitForDevPath(r Resource, maxRetries int) (string, error) {
 }
 
 func getDevPath(r Resource) (string, error) {
-	out, err := exec.Command("linstor", "-m", "ls-rsc").CombinedOutput()
+	list, err := r.client().ListResources(context.Background())
 	if err != nil {
 		return "", err
 	}
 
-	list := resList{}
-	if err := json.Unmarshal(out, &list); err != nil {
-		return "", err
-	}
-
 	// Traverse all the volume states to find volume 0 of our resource.
 	// Assume volume 0 is the one we want.
 	vol := &volInfo{}
-	for _, res := range list[0].ResourceStates {
-		if r.Name == res.RscName {
-			for _, v := range res.VlmStates {
-				if v.VlmNr == 0 {
-					vol = &v
+	if len(list) > 0 {
+		for _, res := range list[0].ResourceStates {
+			if r.Name == res.RscName {
+				for _, v := range res.VlmStates {
+					if v.VlmNr == 0 {
+						vol = &v
+					}
 				}
 			}
 		}
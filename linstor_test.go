@@ -0,0 +1,119 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeClient is a minimal in-memory Client for exercising Resource methods
+// without shelling out or reaching a controller. The err fields let tests
+// inject a failure from the corresponding method; all other calls succeed.
+type fakeClient struct {
+	list resList
+
+	createSnapshotErr   error
+	deleteSnapshotErr   error
+	snapshots           snapshotList
+	listSnapshotsErr    error
+	restoreSnapshotErr  error
+	rollbackSnapshotErr error
+}
+
+func (f *fakeClient) CreateResourceDefinition(ctx context.Context, name string) error {
+	return nil
+}
+func (f *fakeClient) CreateVolumeDefinition(ctx context.Context, name string, sizeKiB uint64) error {
+	return nil
+}
+func (f *fakeClient) CreateResource(ctx context.Context, name, node, storagePool string, diskless bool) error {
+	return nil
+}
+func (f *fakeClient) DeleteResource(ctx context.Context, name, node string) error {
+	return nil
+}
+func (f *fakeClient) DeleteResourceDefinition(ctx context.Context, name string) error {
+	return nil
+}
+func (f *fakeClient) ListResources(ctx context.Context) (resList, error) {
+	return f.list, nil
+}
+func (f *fakeClient) SetVolumeDefinitionSize(ctx context.Context, name string, sizeKiB uint64) error {
+	return nil
+}
+func (f *fakeClient) CreateSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return f.createSnapshotErr
+}
+func (f *fakeClient) DeleteSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return f.deleteSnapshotErr
+}
+func (f *fakeClient) ListSnapshots(ctx context.Context, resourceName string) (snapshotList, error) {
+	return f.snapshots, f.listSnapshotsErr
+}
+func (f *fakeClient) RestoreSnapshot(ctx context.Context, resourceName, snapName, targetName string) error {
+	return f.restoreSnapshotErr
+}
+func (f *fakeClient) RollbackSnapshot(ctx context.Context, resourceName, snapName string) error {
+	return f.rollbackSnapshotErr
+}
+
+func TestResourceExists(t *testing.T) {
+	fc := &fakeClient{list: resList{{Resources: []resInfo{{Name: "foo"}}}}}
+
+	ok, err := (Resource{Name: "foo", Client: fc}).Exists()
+	if err != nil {
+		t.Fatalf("Exists returned error: %v", err)
+	}
+	if !ok {
+		t.Error("expected resource \"foo\" to exist")
+	}
+
+	ok, err = (Resource{Name: "bar", Client: fc}).Exists()
+	if err != nil {
+		t.Fatalf("Exists returned error: %v", err)
+	}
+	if ok {
+		t.Error("expected resource \"bar\" to not exist")
+	}
+}
+
+func TestResourceOnNode(t *testing.T) {
+	fc := &fakeClient{list: resList{{Resources: []resInfo{
+		{Name: "foo", NodeName: "node-a"},
+	}}}}
+
+	r := Resource{Name: "foo", Client: fc}
+
+	present, err := r.OnNode("node-a")
+	if err != nil {
+		t.Fatalf("OnNode returned error: %v", err)
+	}
+	if !present {
+		t.Error("expected resource \"foo\" to be present on node-a")
+	}
+
+	present, err = r.OnNode("node-b")
+	if err != nil {
+		t.Fatalf("OnNode returned error: %v", err)
+	}
+	if present {
+		t.Error("expected resource \"foo\" to not be present on node-b")
+	}
+}
@@ -0,0 +1,307 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+)
+
+// MountPoint is a single entry parsed out of /proc/mounts.
+type MountPoint struct {
+	Device string
+	Path   string
+	Type   string
+	Opts   []string
+}
+
+// Mounter abstracts the mount-related system calls FSUtil needs, the same
+// way Kubernetes' mount.Interface decouples volume plugins from the host's
+// mount table. OSMounter is safe for concurrent use; FakeMounter is not.
+type Mounter interface {
+	// Mount mounts source at target as fstype, passing options straight
+	// through to the mount command (e.g. "ro", "noatime", "discard", or
+	// "bind" for a bind mount).
+	Mount(source, target, fstype string, options []string) error
+	// Unmount unmounts target. It must be a no-op if target isn't mounted.
+	Unmount(target string) error
+	// IsMountPoint reports whether path is currently a mount point.
+	IsMountPoint(path string) (bool, error)
+	// List returns every entry currently in the mount table.
+	List() ([]MountPoint, error)
+	// SafeFormatAndMount formats source with fstype if and only if it's
+	// unformatted, then mounts it at target. See OSMounter.SafeFormatAndMount
+	// for how "unformatted" is decided and when fsck is run.
+	SafeFormatAndMount(source, target, fstype string, options []string) error
+}
+
+// OSMounter is the default Mounter, backed by the mount, umount, and fsck
+// binaries and /proc/mounts.
+type OSMounter struct{}
+
+// NewOSMounter returns a Mounter that shells out to the host's mount tools.
+func NewOSMounter() *OSMounter {
+	return &OSMounter{}
+}
+
+// Mount implements Mounter.
+func (m *OSMounter) Mount(source, target, fstype string, options []string) error {
+	args := []string{}
+	if fstype != "" {
+		args = append(args, "-t", fstype)
+	}
+	if len(options) > 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	args = append(args, source, target)
+
+	out, err := exec.Command("mount", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to mount %q at %q: %v: %s", source, target, err, out)
+	}
+	return nil
+}
+
+// Unmount implements Mounter.
+func (m *OSMounter) Unmount(target string) error {
+	mounted, err := m.IsMountPoint(target)
+	if err != nil {
+		return err
+	}
+	if !mounted {
+		return nil
+	}
+
+	out, err := exec.Command("umount", target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to unmount %q: %v: %s", target, err, out)
+	}
+	return nil
+}
+
+// IsMountPoint implements Mounter.
+func (m *OSMounter) IsMountPoint(path string) (bool, error) {
+	mounts, err := m.List()
+	if err != nil {
+		return false, err
+	}
+
+	for _, mnt := range mounts {
+		if mnt.Path == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// procMountsRetries is how many times List retries reading /proc/mounts if
+// it sees a short read, mirroring the consistent-read loop Kubernetes' mount
+// utilities use to cope with /proc/mounts changing mid-read.
+const procMountsRetries = 3
+
+// List implements Mounter by parsing /proc/mounts.
+func (m *OSMounter) List() ([]MountPoint, error) {
+	var content []byte
+	var err error
+
+	for i := 0; i < procMountsRetries; i++ {
+		content, err = ioutil.ReadFile("/proc/mounts")
+		if err != nil {
+			return nil, fmt.Errorf("unable to read /proc/mounts: %v", err)
+		}
+
+		mounts, parseErr := parseProcMounts(content)
+		if parseErr == nil {
+			return mounts, nil
+		}
+		err = parseErr
+	}
+
+	return nil, fmt.Errorf("inconsistent /proc/mounts after %d attempts: %v", procMountsRetries, err)
+}
+
+func parseProcMounts(content []byte) ([]MountPoint, error) {
+	var mounts []MountPoint
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("wrong number of fields in /proc/mounts line %q: expected 6, got %d", line, len(fields))
+		}
+
+		mounts = append(mounts, MountPoint{
+			Device: fields[0],
+			Path:   fields[1],
+			Type:   fields[2],
+			Opts:   strings.Split(fields[3], ","),
+		})
+	}
+
+	return mounts, nil
+}
+
+// fsckErrCorrected and fsckErrUncorrected are the non-zero fsck(8) exit
+// codes this package accepts as success: 1 means errors were found and
+// corrected, 4 means errors were found but not corrected. Any other non-zero
+// code (notably 8, "operational error") makes SafeFormatAndMount fail
+// without formatting; whether a device gets mkfs is decided by blkid alone.
+const (
+	fsckErrCorrected   = 1
+	fsckErrUncorrected = 4
+)
+
+// SafeFormatAndMount implements Mounter. If source has no detectable
+// filesystem at all, it's formatted with fstype before mounting. If source
+// already has a filesystem, fsck is run against it first (exit code 0, 1, or
+// 4 are treated as success) and it's mounted as-is; any other existing
+// filesystem than fstype is refused.
+func (m *OSMounter) SafeFormatAndMount(source, target, fstype string, options []string) error {
+	existingFS, err := checkFSType(source)
+	if err != nil {
+		return fmt.Errorf("unable to format %q: %v", source, err)
+	}
+
+	shouldFormat, ok := decideFormat(existingFS, fstype)
+	if !ok {
+		return fmt.Errorf("device %q already formatted with %q filesystem, refusing to mount as %q", source, existingFS, fstype)
+	}
+
+	if shouldFormat {
+		out, err := exec.Command("mkfs", "-t", fstype, source).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("couldn't create %s filesystem on %q: %v: %s", fstype, source, err, out)
+		}
+	} else {
+		if err := m.fsck(source); err != nil {
+			return fmt.Errorf("unable to format %q: %v", source, err)
+		}
+	}
+
+	return m.Mount(source, target, fstype, options)
+}
+
+// decideFormat reports whether a device whose existing filesystem (per
+// blkid) is existingFS should be formatted with fstype before mounting.
+// ok is false if existingFS is already some filesystem other than fstype,
+// meaning SafeFormatAndMount must refuse the mount instead of formatting or
+// fscking.
+func decideFormat(existingFS, fstype string) (shouldFormat, ok bool) {
+	if existingFS == "" {
+		return true, true
+	}
+	if existingFS != fstype {
+		return false, false
+	}
+	return false, true
+}
+
+// fsckSuccess reports whether exitCode from fsck(8) means source is usable
+// enough to mount: 0 (no errors), fsckErrCorrected (errors found and
+// corrected), or fsckErrUncorrected (errors found but not corrected, yet
+// the filesystem still mounts). Any other code (notably 8, "operational
+// error", which fsck returns for a device with no recognizable filesystem)
+// is not.
+func fsckSuccess(exitCode int) bool {
+	switch exitCode {
+	case 0, fsckErrCorrected, fsckErrUncorrected:
+		return true
+	default:
+		return false
+	}
+}
+
+// fsck runs fsck against source, treating exit codes 0, 1 (errors
+// corrected), and 4 (errors not corrected, but the filesystem is usable
+// enough to mount) as success.
+func (m *OSMounter) fsck(source string) error {
+	err := exec.Command("fsck", "-a", source).Run()
+	if err == nil {
+		return nil
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return fmt.Errorf("unable to run fsck on %q: %v", source, err)
+	}
+
+	if !fsckSuccess(exitErr.ExitCode()) {
+		return fmt.Errorf("fsck found uncorrectable errors on %q: %v", source, err)
+	}
+	return nil
+}
+
+// FakeMounter is an in-memory Mounter for unit tests. It records every
+// Mount/Unmount call and never touches the host's mount table.
+type FakeMounter struct {
+	MountPoints []MountPoint
+	Log         []string
+}
+
+// NewFakeMounter returns a FakeMounter with no mounts recorded.
+func NewFakeMounter() *FakeMounter {
+	return &FakeMounter{}
+}
+
+// Mount implements Mounter.
+func (m *FakeMounter) Mount(source, target, fstype string, options []string) error {
+	m.Log = append(m.Log, fmt.Sprintf("mount %s %s -t %s -o %s", source, target, fstype, strings.Join(options, ",")))
+	m.MountPoints = append(m.MountPoints, MountPoint{Device: source, Path: target, Type: fstype, Opts: options})
+	return nil
+}
+
+// Unmount implements Mounter.
+func (m *FakeMounter) Unmount(target string) error {
+	m.Log = append(m.Log, fmt.Sprintf("unmount %s", target))
+
+	for i, mnt := range m.MountPoints {
+		if mnt.Path == target {
+			m.MountPoints = append(m.MountPoints[:i], m.MountPoints[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// IsMountPoint implements Mounter.
+func (m *FakeMounter) IsMountPoint(path string) (bool, error) {
+	for _, mnt := range m.MountPoints {
+		if mnt.Path == path {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// List implements Mounter.
+func (m *FakeMounter) List() ([]MountPoint, error) {
+	return m.MountPoints, nil
+}
+
+// SafeFormatAndMount implements Mounter by recording the call and mounting
+// unconditionally; FakeMounter never actually formats anything.
+func (m *FakeMounter) SafeFormatAndMount(source, target, fstype string, options []string) error {
+	m.Log = append(m.Log, fmt.Sprintf("safeFormatAndMount %s %s -t %s", source, target, fstype))
+	return m.Mount(source, target, fstype, options)
+}
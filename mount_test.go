@@ -0,0 +1,81 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import "testing"
+
+func TestDecideFormat(t *testing.T) {
+	cases := []struct {
+		name       string
+		existingFS string
+		fstype     string
+		wantFormat bool
+		wantOK     bool
+	}{
+		{"unformatted device gets mkfs", "", "ext4", true, true},
+		{"already-correct filesystem gets fsck, not mkfs", "ext4", "ext4", false, true},
+		{"mismatched filesystem is refused", "xfs", "ext4", false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gotFormat, gotOK := decideFormat(c.existingFS, c.fstype)
+			if gotFormat != c.wantFormat || gotOK != c.wantOK {
+				t.Errorf("decideFormat(%q, %q) = (%v, %v), want (%v, %v)",
+					c.existingFS, c.fstype, gotFormat, gotOK, c.wantFormat, c.wantOK)
+			}
+		})
+	}
+}
+
+func TestFsckSuccess(t *testing.T) {
+	cases := []struct {
+		exitCode int
+		want     bool
+	}{
+		{0, true},
+		{fsckErrCorrected, true},
+		{fsckErrUncorrected, true},
+		{8, false},
+		{2, false},
+		{1 << 8, false},
+	}
+
+	for _, c := range cases {
+		if got := fsckSuccess(c.exitCode); got != c.want {
+			t.Errorf("fsckSuccess(%d) = %v, want %v", c.exitCode, got, c.want)
+		}
+	}
+}
+
+func TestFakeMounterSafeFormatAndMount(t *testing.T) {
+	m := NewFakeMounter()
+
+	if err := m.SafeFormatAndMount("/dev/drbd0", "/mnt/data", "ext4", []string{"noatime"}); err != nil {
+		t.Fatalf("SafeFormatAndMount returned error: %v", err)
+	}
+
+	mounted, err := m.IsMountPoint("/mnt/data")
+	if err != nil {
+		t.Fatalf("IsMountPoint returned error: %v", err)
+	}
+	if !mounted {
+		t.Error("expected /mnt/data to be mounted after SafeFormatAndMount")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// PublishMode selects how Resource.Publish exposes a resource's device to a
+// consumer: as a mounted filesystem, or as a raw block device.
+type PublishMode int
+
+const (
+	// PublishFilesystem formats (if needed) and mounts the resource at the
+	// target path, the same as FSUtil.Mount.
+	PublishFilesystem PublishMode = iota
+	// PublishBlock bind-mounts the resource's raw device node onto the
+	// target path, which must be a regular file.
+	PublishBlock
+)
+
+// PublishOptions customizes how Resource.Publish exposes a resource.
+type PublishOptions struct {
+	// Readonly mounts the target read-only.
+	Readonly bool
+	// MountFlags are passed straight through to the mount command, e.g.
+	// "noatime", "discard".
+	MountFlags []string
+	// FsType is the filesystem to format/expect. Only used with
+	// PublishFilesystem.
+	FsType string
+}
+
+func (o PublishOptions) mountFlags() []string {
+	flags := append([]string{}, o.MountFlags...)
+	if o.Readonly {
+		flags = append(flags, "ro")
+	}
+	return flags
+}
+
+// Publish exposes r at target in the given PublishMode, separating "device
+// is ready" (WaitForDevPath) from "device is exposed to a consumer". If
+// target doesn't exist, Publish creates it: as a directory for
+// PublishFilesystem, or as a regular file for PublishBlock.
+func (r Resource) Publish(target string, mode PublishMode, opts PublishOptions) error {
+	device, err := WaitForDevPath(r, 3)
+	if err != nil {
+		return fmt.Errorf("unable to publish resource %s, couldn't find device path: %v", r.Name, err)
+	}
+
+	switch mode {
+	case PublishBlock:
+		return r.publishBlock(device, target, opts)
+	case PublishFilesystem:
+		f := FSUtil{Resource: &r, FSType: opts.FsType}
+		if err := exec.Command("mkdir", "-p", target).Run(); err != nil {
+			return fmt.Errorf("unable to publish resource %s, failed to make target directory: %v", r.Name, err)
+		}
+		if err := f.mounter().SafeFormatAndMount(device, target, opts.FsType, opts.mountFlags()); err != nil {
+			return fmt.Errorf("unable to publish resource %s: %v", r.Name, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unable to publish resource %s: unknown publish mode %d", r.Name, mode)
+	}
+}
+
+// publishBlock bind-mounts device onto target, a regular file created if it
+// doesn't already exist, exposing the resource as a raw block device to the
+// consumer.
+func (r Resource) publishBlock(device, target string, opts PublishOptions) error {
+	f, err := os.OpenFile(target, os.O_CREATE, 0660)
+	if err != nil {
+		return fmt.Errorf("unable to publish block device at %q: %v", target, err)
+	}
+	f.Close()
+
+	flags := append([]string{"bind"}, opts.mountFlags()...)
+	if err := r.mounter().Mount(device, target, "", flags); err != nil {
+		return fmt.Errorf("unable to bind mount %q at %q: %v", device, target, err)
+	}
+	return nil
+}
+
+// Unpublish reverses Publish, unmounting target. It's a no-op if target
+// isn't currently mounted.
+func (r Resource) Unpublish(target string) error {
+	if err := r.mounter().Unmount(target); err != nil {
+		return fmt.Errorf("unable to unpublish resource %s from %q: %v", r.Name, target, err)
+	}
+	return nil
+}
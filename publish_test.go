@@ -0,0 +1,85 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPublishBlock(t *testing.T) {
+	fm := NewFakeMounter()
+	r := Resource{Name: "foo", Mounter: fm}
+
+	target := filepath.Join(t.TempDir(), "block-target")
+	opts := PublishOptions{Readonly: true, MountFlags: []string{"noatime"}}
+
+	if err := r.publishBlock("/dev/drbd0", target, opts); err != nil {
+		t.Fatalf("publishBlock returned error: %v", err)
+	}
+
+	if _, err := os.Stat(target); err != nil {
+		t.Fatalf("expected target file to be created: %v", err)
+	}
+
+	mounted, err := fm.IsMountPoint(target)
+	if err != nil {
+		t.Fatalf("IsMountPoint returned error: %v", err)
+	}
+	if !mounted {
+		t.Error("expected target to be recorded as mounted on the injected FakeMounter")
+	}
+
+	mp := fm.MountPoints[0]
+	if mp.Device != "/dev/drbd0" {
+		t.Errorf("got device %q, want /dev/drbd0", mp.Device)
+	}
+	wantOpts := []string{"bind", "noatime", "ro"}
+	if len(mp.Opts) != len(wantOpts) {
+		t.Fatalf("got opts %v, want %v", mp.Opts, wantOpts)
+	}
+	for i, o := range wantOpts {
+		if mp.Opts[i] != o {
+			t.Errorf("got opts %v, want %v", mp.Opts, wantOpts)
+			break
+		}
+	}
+}
+
+func TestUnpublish(t *testing.T) {
+	fm := NewFakeMounter()
+	r := Resource{Name: "foo", Mounter: fm}
+
+	if err := fm.Mount("/dev/drbd0", "/mnt/data", "", nil); err != nil {
+		t.Fatalf("failed to seed FakeMounter: %v", err)
+	}
+
+	if err := r.Unpublish("/mnt/data"); err != nil {
+		t.Fatalf("Unpublish returned error: %v", err)
+	}
+
+	mounted, err := fm.IsMountPoint("/mnt/data")
+	if err != nil {
+		t.Fatalf("IsMountPoint returned error: %v", err)
+	}
+	if mounted {
+		t.Error("expected /mnt/data to no longer be mounted after Unpublish")
+	}
+}
@@ -0,0 +1,188 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// resizePollInterval and resizePollRetries bound how long Resize waits for
+// a new volume size to propagate to every diskful node.
+const (
+	resizePollInterval = time.Second * 2
+	resizePollRetries  = 15
+)
+
+// Resize sets r's volume definition size to newSizeKiB and waits until every
+// diskful node reports a gross size of at least newSizeKiB before returning.
+// On a shrink that condition already holds, so Resize does not wait for the
+// smaller size to propagate.
+func (r Resource) Resize(newSizeKiB uint64) error {
+	ctx := context.Background()
+
+	if err := r.client().SetVolumeDefinitionSize(ctx, r.Name, newSizeKiB); err != nil {
+		return fmt.Errorf("unable to resize resource %s to %d KiB: %v", r.Name, newSizeKiB, err)
+	}
+
+	for i := 0; i < resizePollRetries; i++ {
+		list, err := r.client().ListResources(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to confirm resize of resource %s: %v", r.Name, err)
+		}
+
+		if allNodesResized(list, r.Name, newSizeKiB) {
+			return nil
+		}
+
+		time.Sleep(resizePollInterval)
+	}
+
+	return fmt.Errorf("timed out waiting for resource %s to grow to %d KiB on all nodes", r.Name, newSizeKiB)
+}
+
+// allNodesResized reports whether every diskful state of resName in list
+// already reflects newSizeKiB.
+func allNodesResized(list resList, resName string, newSizeKiB uint64) bool {
+	found := false
+
+	for _, res := range list[0].ResourceStates {
+		if res.RscName != resName {
+			continue
+		}
+		for _, v := range res.VlmStates {
+			if v.VlmNr != 0 || !v.HasDisk {
+				continue
+			}
+			found = true
+			if uint64(v.GrossSize) < newSizeKiB {
+				return false
+			}
+		}
+	}
+
+	return found
+}
+
+// OnlineResize grows r's LINSTOR volume to newSizeKiB, then expands the
+// filesystem in place. After the volume is grown, it checks that target is
+// actually where r's device is mounted and returns an error without touching
+// the filesystem if not, so it never grows a filesystem the caller didn't
+// mean to touch.
+func (r Resource) OnlineResize(target string, newSizeKiB uint64) error {
+	if err := r.Resize(newSizeKiB); err != nil {
+		return err
+	}
+
+	device, err := WaitForDevPath(r, 3)
+	if err != nil {
+		return fmt.Errorf("unable to online resize resource %s, couldn't find device path: %v", r.Name, err)
+	}
+
+	f := FSUtil{Resource: &r}
+	mounts, err := f.mounter().List()
+	if err != nil {
+		return fmt.Errorf("unable to online resize resource %s: %v", r.Name, err)
+	}
+	if !deviceMountedAt(mounts, device, target) {
+		return fmt.Errorf("unable to online resize resource %s: device %q is not mounted at %q", r.Name, device, target)
+	}
+
+	if err := f.ExpandFS(device); err != nil {
+		return fmt.Errorf("unable to online resize resource %s: %v", r.Name, err)
+	}
+
+	return nil
+}
+
+// deviceMountedAt reports whether mounts contains an entry mounting device
+// at target.
+func deviceMountedAt(mounts []MountPoint, device, target string) bool {
+	for _, m := range mounts {
+		if m.Device == device && m.Path == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnknownFilesystem is returned by FSUtil.ExpandFS when the device's
+// filesystem isn't one this package knows how to grow.
+type ErrUnknownFilesystem struct {
+	FSType string
+}
+
+func (e ErrUnknownFilesystem) Error() string {
+	return fmt.Sprintf("don't know how to grow filesystem %q", e.FSType)
+}
+
+// ExpandFS detects the filesystem already present on devicePath and grows it
+// in place to fill the (already-resized) underlying device.
+func (f FSUtil) ExpandFS(devicePath string) error {
+	fsType, err := checkFSType(devicePath)
+	if err != nil {
+		return fmt.Errorf("unable to determine filesystem on %q: %v", devicePath, err)
+	}
+
+	var cmd *exec.Cmd
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		cmd = exec.Command("resize2fs", devicePath)
+	case "xfs":
+		mountPoint, err := f.mountPointFor(devicePath)
+		if err != nil {
+			return err
+		}
+		cmd = exec.Command("xfs_growfs", mountPoint)
+	case "btrfs":
+		mountPoint, err := f.mountPointFor(devicePath)
+		if err != nil {
+			return err
+		}
+		cmd = exec.Command("btrfs", "filesystem", "resize", "max", mountPoint)
+	default:
+		return ErrUnknownFilesystem{FSType: fsType}
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to grow %s filesystem on %q: %v: %s", fsType, devicePath, err, out)
+	}
+
+	return nil
+}
+
+// mountPointFor finds where devicePath is currently mounted; xfs_growfs and
+// btrfs operate against the mount point rather than the block device.
+func (f FSUtil) mountPointFor(devicePath string) (string, error) {
+	mounts, err := f.mounter().List()
+	if err != nil {
+		return "", fmt.Errorf("unable to find mount point for %q: %v", devicePath, err)
+	}
+
+	for _, mnt := range mounts {
+		if mnt.Device == devicePath {
+			return mnt.Path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%q is not currently mounted", devicePath)
+}
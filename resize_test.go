@@ -0,0 +1,65 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import "testing"
+
+func TestAllNodesResized(t *testing.T) {
+	list := resList{{
+		ResourceStates: []struct {
+			RequiresAdjust bool      `json:"requires_adjust"`
+			RscName        string    `json:"rsc_name"`
+			IsPrimary      bool      `json:"is_primary"`
+			VlmStates      []volInfo `json:"vlm_states"`
+			IsPresent      bool      `json:"is_present"`
+			NodeName       string    `json:"node_name"`
+		}{
+			{RscName: "foo", NodeName: "node-a", VlmStates: []volInfo{{VlmNr: 0, HasDisk: true, GrossSize: 2048}}},
+			{RscName: "foo", NodeName: "node-b", VlmStates: []volInfo{{VlmNr: 0, HasDisk: true, GrossSize: 1024}}},
+			{RscName: "foo", NodeName: "node-c", VlmStates: []volInfo{{VlmNr: 0, HasDisk: false, GrossSize: 0}}},
+		},
+	}}
+
+	if allNodesResized(list, "foo", 2048) {
+		t.Error("expected allNodesResized to be false while node-b is still at the old size")
+	}
+	if !allNodesResized(list, "foo", 1024) {
+		t.Error("expected allNodesResized to be true once every diskful node has reached the size")
+	}
+	if allNodesResized(list, "bar", 0) {
+		t.Error("expected allNodesResized to be false for a resource with no matching state")
+	}
+}
+
+func TestDeviceMountedAt(t *testing.T) {
+	mounts := []MountPoint{
+		{Device: "/dev/drbd0", Path: "/mnt/data"},
+		{Device: "/dev/drbd1", Path: "/mnt/other"},
+	}
+
+	if !deviceMountedAt(mounts, "/dev/drbd0", "/mnt/data") {
+		t.Error("expected /dev/drbd0 to be reported as mounted at /mnt/data")
+	}
+	if deviceMountedAt(mounts, "/dev/drbd0", "/mnt/other") {
+		t.Error("expected /dev/drbd0 to not be reported as mounted at /mnt/other")
+	}
+	if deviceMountedAt(mounts, "/dev/drbd9", "/mnt/data") {
+		t.Error("expected an unmounted device to not be reported as mounted anywhere")
+	}
+}
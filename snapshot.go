@@ -0,0 +1,194 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type snapshotList []struct {
+	Name         string   `json:"name"`
+	ResourceName string   `json:"resource_name"`
+	NodeNames    []string `json:"node_names"`
+	Flags        []string `json:"flags"`
+}
+
+// ErrResourceNotFound is returned when an operation is attempted against a
+// resource that doesn't exist on the controller.
+type ErrResourceNotFound struct {
+	ResourceName string
+}
+
+func (e ErrResourceNotFound) Error() string {
+	return fmt.Sprintf("resource %s not found", e.ResourceName)
+}
+
+// ErrTargetExists is returned by Snapshot.Restore when the target resource
+// name is already taken by another resource definition.
+type ErrTargetExists struct {
+	ResourceName string
+}
+
+func (e ErrTargetExists) Error() string {
+	return fmt.Sprintf("resource %s already exists, refusing to restore over it", e.ResourceName)
+}
+
+// ErrRestoreInProgress is returned by Snapshot.Restore when the controller
+// reports that target is still being restored from a previous
+// RestoreSnapshot call.
+type ErrRestoreInProgress struct {
+	ResourceName string
+}
+
+func (e ErrRestoreInProgress) Error() string {
+	return fmt.Sprintf("restore of resource %s is already in progress", e.ResourceName)
+}
+
+// ErrSnapshotExists is returned by Snapshot.Create when a snapshot with the
+// requested name already exists on the resource.
+type ErrSnapshotExists struct {
+	ResourceName string
+	SnapName     string
+}
+
+func (e ErrSnapshotExists) Error() string {
+	return fmt.Sprintf("snapshot %s of resource %s already exists", e.SnapName, e.ResourceName)
+}
+
+// Snapshot is a point-in-time snapshot of a Resource's volumes.
+type Snapshot struct {
+	Name         string
+	ResourceName string
+	Client       Client
+}
+
+func (s Snapshot) client() Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return DefaultClient
+}
+
+// Create takes a new snapshot named name of resource.
+func (s *Snapshot) Create(resource *Resource, name string) error {
+	ok, err := resource.Exists()
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot %s: %v", name, err)
+	}
+	if !ok {
+		return ErrResourceNotFound{ResourceName: resource.Name}
+	}
+
+	if err := resource.client().CreateSnapshot(context.Background(), resource.Name, name); err != nil {
+		var merr MultiError
+		if errors.As(err, &merr) && merr.Any((*LinstorError).IsAlreadyExists) {
+			return ErrSnapshotExists{ResourceName: resource.Name, SnapName: name}
+		}
+		return fmt.Errorf("unable to create snapshot %s of resource %s: %w", name, resource.Name, err)
+	}
+
+	s.Name = name
+	s.ResourceName = resource.Name
+	s.Client = resource.Client
+
+	return nil
+}
+
+// Delete removes the snapshot.
+func (s Snapshot) Delete() error {
+	if err := s.client().DeleteSnapshot(context.Background(), s.ResourceName, s.Name); err != nil {
+		return fmt.Errorf("unable to delete snapshot %s of resource %s: %v", s.Name, s.ResourceName, err)
+	}
+	return nil
+}
+
+// List returns every snapshot of resource.
+func (s Snapshot) List(resource *Resource) ([]Snapshot, error) {
+	list, err := resource.client().ListSnapshots(context.Background(), resource.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list snapshots of resource %s: %v", resource.Name, err)
+	}
+
+	snaps := make([]Snapshot, 0, len(list))
+	for _, snap := range list {
+		snaps = append(snaps, Snapshot{
+			Name:         snap.Name,
+			ResourceName: snap.ResourceName,
+			Client:       resource.Client,
+		})
+	}
+	return snaps, nil
+}
+
+// Restore creates a new resource definition named target.Name from the
+// snapshot, leaving the snapshot itself intact.
+func (s Snapshot) Restore(target *Resource) error {
+	ok, err := target.Exists()
+	if err != nil {
+		return fmt.Errorf("unable to restore snapshot %s to resource %s: %v", s.Name, target.Name, err)
+	}
+	if ok {
+		return ErrTargetExists{ResourceName: target.Name}
+	}
+
+	if err := s.client().RestoreSnapshot(context.Background(), s.ResourceName, s.Name, target.Name); err != nil {
+		var merr MultiError
+		if errors.As(err, &merr) && merr.Any((*LinstorError).IsInUse) {
+			return ErrRestoreInProgress{ResourceName: target.Name}
+		}
+		return fmt.Errorf("unable to restore snapshot %s to resource %s: %v", s.Name, target.Name, err)
+	}
+	return nil
+}
+
+// Rollback reverts the snapshot's source resource back to the state it was
+// in when the snapshot was taken.
+func (s Snapshot) Rollback() error {
+	if err := s.client().RollbackSnapshot(context.Background(), s.ResourceName, s.Name); err != nil {
+		return fmt.Errorf("unable to roll back resource %s to snapshot %s: %v", s.ResourceName, s.Name, err)
+	}
+	return nil
+}
+
+// Clone snapshots r and restores it into a new resource definition named
+// newName, in one step.
+func (r Resource) Clone(newName string) (*Resource, error) {
+	snapName := fmt.Sprintf("%s-clone-%s", r.Name, newName)
+
+	snap := Snapshot{}
+	if err := snap.Create(&r, snapName); err != nil {
+		return nil, fmt.Errorf("unable to clone resource %s to %s: %v", r.Name, newName, err)
+	}
+
+	clone := &Resource{
+		Name:        newName,
+		Redundancy:  r.Redundancy,
+		StoragePool: r.StoragePool,
+		SizeKiB:     r.SizeKiB,
+		Client:      r.Client,
+	}
+
+	if err := snap.Restore(clone); err != nil {
+		return nil, fmt.Errorf("unable to clone resource %s to %s: %v", r.Name, newName, err)
+	}
+
+	return clone, nil
+}
@@ -0,0 +1,133 @@
+/*
+* A helpful library to interact with Linstor
+* Copyright © 2018 LINBIT USA LCC
+*
+* This program is free software; you can redistribute it and/or modify
+* it under the terms of the GNU General Public License as published by
+* the Free Software Foundation; either version 2 of the License, or
+* (at your option) any later version.
+*
+* This program is distributed in the hope that it will be useful,
+* but WITHOUT ANY WARRANTY; without even the implied warranty of
+* MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+* GNU General Public License for more details.
+*
+* You should have received a copy of the GNU General Public License
+* along with this program; if not, see <http://www.gnu.org/licenses/>.
+ */
+
+package linstor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotCreate(t *testing.T) {
+	t.Run("resource not found", func(t *testing.T) {
+		fc := &fakeClient{}
+		r := Resource{Name: "foo", Client: fc}
+
+		var snap Snapshot
+		err := snap.Create(&r, "snap1")
+
+		var notFound ErrResourceNotFound
+		if !errors.As(err, &notFound) {
+			t.Fatalf("Create returned %v, want ErrResourceNotFound", err)
+		}
+	})
+
+	t.Run("snapshot already exists", func(t *testing.T) {
+		fc := &fakeClient{
+			list:              resList{{Resources: []resInfo{{Name: "foo"}}}},
+			createSnapshotErr: MultiError{&LinstorError{Message: "Snapshot 'snap1' already exists."}},
+		}
+		r := Resource{Name: "foo", Client: fc}
+
+		var snap Snapshot
+		err := snap.Create(&r, "snap1")
+
+		var exists ErrSnapshotExists
+		if !errors.As(err, &exists) {
+			t.Fatalf("Create returned %v, want ErrSnapshotExists", err)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		fc := &fakeClient{list: resList{{Resources: []resInfo{{Name: "foo"}}}}}
+		r := Resource{Name: "foo", Client: fc}
+
+		var snap Snapshot
+		if err := snap.Create(&r, "snap1"); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+		if snap.Name != "snap1" || snap.ResourceName != "foo" {
+			t.Errorf("got Snapshot{Name: %q, ResourceName: %q}, want {snap1, foo}", snap.Name, snap.ResourceName)
+		}
+	})
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Run("target already exists", func(t *testing.T) {
+		fc := &fakeClient{list: resList{{Resources: []resInfo{{Name: "clone"}}}}}
+		snap := Snapshot{Name: "snap1", ResourceName: "foo", Client: fc}
+		target := Resource{Name: "clone", Client: fc}
+
+		err := snap.Restore(&target)
+
+		var targetExists ErrTargetExists
+		if !errors.As(err, &targetExists) {
+			t.Fatalf("Restore returned %v, want ErrTargetExists", err)
+		}
+	})
+
+	t.Run("restore in progress", func(t *testing.T) {
+		fc := &fakeClient{
+			restoreSnapshotErr: MultiError{&LinstorError{Message: "Resource 'clone' is still in use."}},
+		}
+		snap := Snapshot{Name: "snap1", ResourceName: "foo", Client: fc}
+		target := Resource{Name: "clone", Client: fc}
+
+		err := snap.Restore(&target)
+
+		var inProgress ErrRestoreInProgress
+		if !errors.As(err, &inProgress) {
+			t.Fatalf("Restore returned %v, want ErrRestoreInProgress", err)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		fc := &fakeClient{}
+		snap := Snapshot{Name: "snap1", ResourceName: "foo", Client: fc}
+		target := Resource{Name: "clone", Client: fc}
+
+		if err := snap.Restore(&target); err != nil {
+			t.Fatalf("Restore returned error: %v", err)
+		}
+	})
+}
+
+func TestSnapshotRollback(t *testing.T) {
+	fc := &fakeClient{rollbackSnapshotErr: errors.New("rollback failed")}
+	snap := Snapshot{Name: "snap1", ResourceName: "foo", Client: fc}
+
+	err := snap.Rollback()
+	if err == nil || !strings.Contains(err.Error(), "rollback failed") {
+		t.Fatalf("Rollback returned %v, want an error mentioning %q", err, "rollback failed")
+	}
+}
+
+func TestResourceClone(t *testing.T) {
+	fc := &fakeClient{list: resList{{Resources: []resInfo{{Name: "foo"}}}}}
+	r := Resource{Name: "foo", Redundancy: "2", StoragePool: "pool1", SizeKiB: 1024, Client: fc}
+
+	clone, err := r.Clone("foo-clone")
+	if err != nil {
+		t.Fatalf("Clone returned error: %v", err)
+	}
+
+	if clone.Name != "foo-clone" || clone.Redundancy != "2" || clone.StoragePool != "pool1" || clone.SizeKiB != 1024 {
+		t.Errorf("got clone %+v, want Name=foo-clone Redundancy=2 StoragePool=pool1 SizeKiB=1024", clone)
+	}
+}